@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ASTag is an ActivityStreams Hashtag attached to a post for each resolved
+// category/tag.
+type ASTag struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Href string `json:"href"`
+}
+
+// ASAttachment is an ActivityStreams Image attachment for an inline image.
+type ASAttachment struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// ASObject is the ActivityStreams document written alongside each post so
+// Fediverse servers can consume it directly.
+type ASObject struct {
+	Context      []string       `json:"@context"`
+	Type         string         `json:"type"`
+	ID           string         `json:"id"`
+	URL          string         `json:"url"`
+	AttributedTo string         `json:"attributedTo"`
+	To           []string       `json:"to"`
+	Published    string         `json:"published,omitempty"`
+	Updated      string         `json:"updated,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	Content      string         `json:"content"`
+	Tag          []ASTag        `json:"tag,omitempty"`
+	Attachment   []ASAttachment `json:"attachment,omitempty"`
+}
+
+func activityPubActorURL() string {
+	if actor := os.Getenv("ACTIVITYPUB_ACTOR_URL"); actor != "" {
+		return actor
+	}
+	return "https://animalsjunction.com/actor"
+}
+
+var asImgSrcRegex = regexp.MustCompile(`<img\s+[^>]*src=["']([^"']+)["'][^>]*>`)
+
+// discoverImages returns every image URL referenced in a post's rendered
+// HTML, used to populate ActivityStreams attachments.
+func discoverImages(contentHTML string) []string {
+	matches := asImgSrcRegex.FindAllStringSubmatch(contentHTML, -1)
+	urls := make([]string, 0, len(matches))
+	for _, m := range matches {
+		urls = append(urls, m[1])
+	}
+	return urls
+}
+
+// buildActivityStreamsObject assembles the ActivityStreams document for a
+// single post: an Article when it has a title, otherwise a Note.
+func buildActivityStreamsObject(title, postURL, contentHTML, published, updated string, tags []string) ASObject {
+	objType := "Note"
+	if strings.TrimSpace(title) != "" {
+		objType = "Article"
+	}
+
+	asTags := make([]ASTag, 0, len(tags))
+	for _, tag := range tags {
+		asTags = append(asTags, ASTag{Type: "Hashtag", Name: "#" + tag, Href: postURL})
+	}
+
+	attachments := make([]ASAttachment, 0)
+	for _, img := range discoverImages(contentHTML) {
+		attachments = append(attachments, ASAttachment{Type: "Image", URL: img})
+	}
+
+	return ASObject{
+		Context:      []string{"https://www.w3.org/ns/activitystreams"},
+		Type:         objType,
+		ID:           postURL,
+		URL:          postURL,
+		AttributedTo: activityPubActorURL(),
+		To:           []string{"https://www.w3.org/ns/activitystreams#Public"},
+		Published:    published,
+		Updated:      updated,
+		Name:         title,
+		Content:      contentHTML,
+		Tag:          asTags,
+		Attachment:   attachments,
+	}
+}
+
+// writeActivityStreamsSibling writes the post's ActivityStreams document to
+// <outputDir>/<slug>.as.json, next to its Markdown file.
+func writeActivityStreamsSibling(outputDir, slug string, obj ASObject) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal activitystreams object: %w", err)
+	}
+	path := fmt.Sprintf("%s/%s.as.json", outputDir, slug)
+	return os.WriteFile(path, data, 0644)
+}
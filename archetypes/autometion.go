@@ -1,18 +1,20 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
 	"os"
-	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 type Post struct {
@@ -33,65 +35,226 @@ func sanitizeFilename(name string) string {
 	return name
 }
 
+// downloadImage fetches imgURL into the content-addressed static/images
+// store, generating responsive/WebP variants for raster images, and returns
+// a Hugo figure/picture shortcode referencing them.
 func downloadImage(imgURL string) (string, error) {
-	parsedURL, err := url.Parse(imgURL)
+	baseDir := filepath.Join("static", "images")
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", err
+	}
+
+	originalPath, ext, err := fetchAndHashImage(imgURL, baseDir)
 	if err != nil {
 		return "", err
 	}
 
-	fileName := fmt.Sprintf("%d_%s", time.Now().UnixNano(), path.Base(parsedURL.Path))
-	filePath := filepath.Join("static", "images", fileName)
+	variants, webpPath, err := processRasterVariants(originalPath, ext)
+	if err != nil {
+		log.Printf("Image variant generation failed for %s: %v", imgURL, err)
+	}
+
+	return buildImageShortcode(ImageAsset{Original: originalPath, Variants: variants, WebP: webpPath}), nil
+}
 
-	os.MkdirAll(filepath.Dir(filePath), 0755)
+var (
+	shortcodeCodeRegex    = regexp.MustCompile(`(?s)\[code(?:\s+lang=["']?([a-zA-Z0-9_+-]*)["']?)?\](.*?)\[/code\]`)
+	shortcodeCaptionRegex = regexp.MustCompile(`(?s)\[caption[^\]]*\](.*?)<img([^>]*)>(.*?)\[/caption\]`)
+	shortcodeGalleryRegex = regexp.MustCompile(`\[gallery\s+ids=["']([0-9,\s]+)["'][^\]]*\]`)
+	shortcodeMapsRegex    = regexp.MustCompile(`\[googlemaps\s+([^\]]+)\]`)
+	latexRegex            = regexp.MustCompile(`\$latex\s+(.*?)\$`)
+	htmlEntityReplacer    = strings.NewReplacer(
+		"&amp;", "&",
+		"&gt;", ">",
+		"&lt;", "<",
+		"&#8220;", "\"",
+		"&#8221;", "\"",
+		"&#8216;", "'",
+		"&#8217;", "'",
+		"&nbsp;", " ",
+	)
+)
 
-	resp, err := http.Get(imgURL)
+// fetchMediaURL resolves a WordPress attachment ID to its source image URL
+// via /wp-json/wp/v2/media/<id>.
+func fetchMediaURL(siteURL, mediaID string) (string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/wp-json/wp/v2/media/%s", siteURL, mediaID))
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d fetching media %s", resp.StatusCode, mediaID)
+	}
 
-	out, err := os.Create(filePath)
-	if err != nil {
+	var media struct {
+		SourceURL string `json:"source_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&media); err != nil {
 		return "", err
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	return "/images/" + fileName, err
+	return media.SourceURL, nil
 }
 
-func convertToMarkdown(html string) string {
-	markdown := html
+// expandShortcodes rewrites WordPress classic-editor shortcodes into the
+// HTML/Hugo-shortcode equivalents goquery/goldmark expect to see. siteURL is
+// used to resolve [gallery] attachment IDs to real image URLs.
+func expandShortcodes(html, siteURL string) string {
+	html = htmlEntityReplacer.Replace(html)
 
-	imgRegex := regexp.MustCompile(`<img\s+[^>]*src=["']([^"']+)["'][^>]*>`)
-	markdown = imgRegex.ReplaceAllStringFunc(markdown, func(match string) string {
-		imgURL := imgRegex.FindStringSubmatch(match)[1]
-		markdownPath, err := downloadImage(imgURL)
-		if err != nil {
-			log.Printf("Image download failed: %v", err)
-			return ""
+	html = shortcodeCodeRegex.ReplaceAllString(html, "<pre><code class=\"language-$1\">$2</code></pre>")
+
+	html = shortcodeCaptionRegex.ReplaceAllStringFunc(html, func(match string) string {
+		parts := shortcodeCaptionRegex.FindStringSubmatch(match)
+		attrs := parts[2]
+		src := ""
+		if m := regexp.MustCompile(`src=["']([^"']+)["']`).FindStringSubmatch(attrs); m != nil {
+			src = m[1]
 		}
-		return fmt.Sprintf("![](%s)", markdownPath)
+		caption := strings.TrimSpace(stripTags(parts[3]))
+		return fmt.Sprintf(`{{< figure src="%s" caption="%s" >}}`, src, caption)
 	})
 
-	conversions := map[string]string{
-		"<h1[^>]*>(.*?)</h1>": "# $1\n",
-		"<h2[^>]*>(.*?)</h2>": "## $1\n",
-		"<h3[^>]*>(.*?)</h3>": "### $1\n",
-		"<p[^>]*>(.*?)</p>":   "$1\n\n",
-		"<strong>(.*?)</strong>": "**$1**",
-		"<em>(.*?)</em>":         "*$1*",
-		"</?[^>]*>":              "",
+	html = shortcodeGalleryRegex.ReplaceAllStringFunc(html, func(match string) string {
+		ids := shortcodeGalleryRegex.FindStringSubmatch(match)[1]
+		var grid strings.Builder
+		grid.WriteString(`<div class="gallery-grid">`)
+		for _, id := range strings.Split(ids, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			src, err := fetchMediaURL(siteURL, id)
+			if err != nil || src == "" {
+				log.Printf("Gallery attachment %s could not be resolved: %v", id, err)
+				continue
+			}
+			grid.WriteString(fmt.Sprintf(`<img class="gallery-image" src="%s">`, src))
+		}
+		grid.WriteString("</div>")
+		return grid.String()
+	})
+
+	html = shortcodeMapsRegex.ReplaceAllString(html, `{{< googlemaps $1 >}}`)
+
+	html = latexRegex.ReplaceAllString(html, "$$$1$$")
+
+	return html
+}
+
+func stripTags(s string) string {
+	return regexp.MustCompile(`<[^>]*>`).ReplaceAllString(s, "")
+}
+
+// convertToMarkdown parses the WordPress HTML with goquery and walks the DOM
+// to build Markdown, then renders the result back through goldmark as a
+// sanity check before returning it. siteURL is passed through to
+// expandShortcodes to resolve [gallery] attachment IDs.
+func convertToMarkdown(html, siteURL string) string {
+	html = expandShortcodes(html, siteURL)
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		log.Printf("Failed to parse post HTML: %v", err)
+		return html
 	}
 
-	for pattern, replacement := range conversions {
-		markdown = regexp.MustCompile(pattern).ReplaceAllString(markdown, replacement)
+	var buf strings.Builder
+	walkNodes(doc.Contents(), &buf)
+	markdown := strings.TrimSpace(buf.String()) + "\n"
+
+	var rendered bytes.Buffer
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := md.Convert([]byte(markdown), &rendered); err != nil {
+		log.Printf("Markdown verification render failed: %v", err)
 	}
 
 	return markdown
 }
 
-func createFrontMatter(post Post) string {
+// walkNodes recursively renders a goquery selection into Markdown.
+func walkNodes(sel *goquery.Selection, buf *strings.Builder) {
+	sel.Each(func(_ int, node *goquery.Selection) {
+		switch goquery.NodeName(node) {
+		case "h1":
+			buf.WriteString("# " + strings.TrimSpace(node.Text()) + "\n\n")
+		case "h2":
+			buf.WriteString("## " + strings.TrimSpace(node.Text()) + "\n\n")
+		case "h3":
+			buf.WriteString("### " + strings.TrimSpace(node.Text()) + "\n\n")
+		case "p":
+			buf.WriteString(renderInline(node) + "\n\n")
+		case "strong", "b":
+			buf.WriteString("**" + strings.TrimSpace(node.Text()) + "**")
+		case "em", "i":
+			buf.WriteString("*" + strings.TrimSpace(node.Text()) + "*")
+		case "blockquote":
+			for _, line := range strings.Split(strings.TrimSpace(node.Text()), "\n") {
+				buf.WriteString("> " + line + "\n")
+			}
+			buf.WriteString("\n")
+		case "pre":
+			lang := ""
+			if class, ok := node.Find("code").Attr("class"); ok {
+				lang = strings.TrimPrefix(class, "language-")
+			}
+			buf.WriteString("```" + lang + "\n" + node.Text() + "\n```\n\n")
+		case "ul":
+			node.Find("li").Each(func(_ int, li *goquery.Selection) {
+				buf.WriteString("- " + strings.TrimSpace(li.Text()) + "\n")
+			})
+			buf.WriteString("\n")
+		case "ol":
+			node.Find("li").Each(func(i int, li *goquery.Selection) {
+				buf.WriteString(fmt.Sprintf("%d. %s\n", i+1, strings.TrimSpace(li.Text())))
+			})
+			buf.WriteString("\n")
+		case "table":
+			renderTable(node, buf)
+		case "img":
+			imgURL, _ := node.Attr("src")
+			shortcode, err := downloadImage(imgURL)
+			if err != nil {
+				log.Printf("Image download failed: %v", err)
+				return
+			}
+			buf.WriteString(shortcode + "\n\n")
+		case "#text":
+			if text := node.Text(); strings.TrimSpace(text) != "" {
+				buf.WriteString(text)
+			}
+		default:
+			walkNodes(node.Contents(), buf)
+		}
+	})
+}
+
+func renderInline(node *goquery.Selection) string {
+	var buf strings.Builder
+	walkNodes(node.Contents(), &buf)
+	return strings.TrimSpace(buf.String())
+}
+
+func renderTable(table *goquery.Selection, buf *strings.Builder) {
+	rows := table.Find("tr")
+	rows.Each(func(i int, row *goquery.Selection) {
+		var cells []string
+		row.Find("th, td").Each(func(_ int, cell *goquery.Selection) {
+			cells = append(cells, strings.TrimSpace(cell.Text()))
+		})
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			sep := make([]string, len(cells))
+			for j := range sep {
+				sep[j] = "---"
+			}
+			buf.WriteString("| " + strings.Join(sep, " | ") + " |\n")
+		}
+	})
+	buf.WriteString("\n")
+}
+
+func createFrontMatter(post Post, taxonomy *TaxonomyCache) string {
 	fm := "---\n"
 	fm += fmt.Sprintf("title: \"%s\"\n", post.Title.Rendered)
 	fm += fmt.Sprintf("date: %s\n", post.Date)
@@ -101,15 +264,15 @@ func createFrontMatter(post Post) string {
 
 	if len(post.Categories) > 0 {
 		fm += "categories:\n"
-		for _, cat := range post.Categories {
-			fm += fmt.Sprintf("  - %d\n", cat)
+		for _, cat := range resolveTerms(post.Categories, taxonomy.Categories) {
+			fm += fmt.Sprintf("  - %q\n", cat)
 		}
 	}
 
 	if len(post.Tags) > 0 {
 		fm += "tags:\n"
-		for _, tag := range post.Tags {
-			fm += fmt.Sprintf("  - %d\n", tag)
+		for _, tag := range resolveTerms(post.Tags, taxonomy.Tags) {
+			fm += fmt.Sprintf("  - %q\n", tag)
 		}
 	}
 
@@ -117,61 +280,107 @@ func createFrontMatter(post Post) string {
 	return fm
 }
 
-func fetchPosts(apiURL string) ([]Post, error) {
-	resp, err := http.Get(apiURL)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var posts []Post
-	err = json.NewDecoder(resp.Body).Decode(&posts)
-	return posts, err
-}
-
 func main() {
-	apiURL := "https://animalsjunction.com/wp-json/wp/v2/posts"
+	siteURL := "https://animalsjunction.com"
+	apiURL := siteURL + "/wp-json/wp/v2/posts"
 	outputDir := "content/posts"
 
+	flags := parsePollerFlags()
 	existingPosts := make(map[string]bool)
+	taxonomy := loadTaxonomyCache(taxonomyCachePath)
+	feedCfg := loadFeedConfig()
+	pollState := loadPollState(pollStatePath)
+	// feedIndex accumulates every synced post's FeedEntry, keyed by slug, so
+	// a delta pass's feed/sitemap still cover posts unchanged since LastSync
+	// instead of only the posts fetched this pass.
+	feedIndex := make(map[string]FeedEntry)
 
 	for {
-		posts, err := fetchPosts(apiURL)
+		// A full (non-delta) pass is the only one that sees every post, so
+		// it's the only one safe to use for deletion reconciliation below.
+		fullPass := pollState.LastSync == ""
+
+		posts, err := fetchPostsSince(apiURL, pollState, flags.Concurrency)
 		if err != nil {
 			log.Printf("Fetch error: %v", err)
-			time.Sleep(4 * time.Second)
+			time.Sleep(flags.Interval)
+			continue
+		}
+		if err := savePollState(pollStatePath, pollState); err != nil {
+			log.Printf("Failed to persist poll state: %v", err)
+		}
+		if len(posts) == 0 {
+			log.Println("No changed posts since last poll")
+			time.Sleep(flags.Interval)
 			continue
 		}
 
+		if err := refreshTaxonomy(siteURL, taxonomy); err != nil {
+			log.Printf("Taxonomy refresh failed: %v", err)
+		} else if err := saveTaxonomyCache(taxonomyCachePath, taxonomy); err != nil {
+			log.Printf("Failed to persist taxonomy cache: %v", err)
+		}
+
 		// Track current posts
 		currentSlugs := make(map[string]bool)
 		for _, post := range posts {
 			currentSlugs[post.Slug] = true
-			
+
 			filename := filepath.Join(outputDir, post.Slug+".md")
-			markdown := convertToMarkdown(post.Content.Rendered)
-			content := createFrontMatter(post) + markdown
+			markdown := convertToMarkdown(post.Content.Rendered, siteURL)
+			content := createFrontMatter(post, taxonomy) + markdown
 
 			os.WriteFile(filename, []byte(content), 0644)
 			log.Printf("Processed: %s", filename)
+
+			postURL := fmt.Sprintf("https://%s/posts/%s/", feedCfg.Domain, post.Slug)
+			asObject := buildActivityStreamsObject(post.Title.Rendered, postURL, post.Content.Rendered,
+				post.Date, post.Modified, append(resolveTerms(post.Categories, taxonomy.Categories), resolveTerms(post.Tags, taxonomy.Tags)...))
+			if err := writeActivityStreamsSibling(outputDir, post.Slug, asObject); err != nil {
+				log.Printf("Failed to write ActivityStreams document for %s: %v", post.Slug, err)
+			}
+
+			feedIndex[post.Slug] = FeedEntry{
+				Title:       post.Title.Rendered,
+				Slug:        post.Slug,
+				ContentHTML: post.Content.Rendered,
+				Published:   parseWPDate(post.Date),
+				Updated:     parseWPDate(post.Modified),
+			}
 		}
 
-		// Remove posts deleted from WordPress
-		for slug := range existingPosts {
-			if !currentSlugs[slug] {
-				filename := filepath.Join(outputDir, slug+".md")
-				err := os.Remove(filename)
-				if err != nil {
-					log.Printf("Failed to delete %s: %v", filename, err)
-				} else {
-					log.Printf("Deleted post: %s", filename)
+		// Deletion reconciliation needs the full post list, which only a
+		// full (non-delta) pass returns; delta passes only add to it.
+		if fullPass {
+			for slug := range existingPosts {
+				if !currentSlugs[slug] {
+					filename := filepath.Join(outputDir, slug+".md")
+					err := os.Remove(filename)
+					if err != nil {
+						log.Printf("Failed to delete %s: %v", filename, err)
+					} else {
+						log.Printf("Deleted post: %s", filename)
+					}
+					delete(feedIndex, slug)
 				}
 			}
+			existingPosts = currentSlugs
+		} else {
+			for slug := range currentSlugs {
+				existingPosts[slug] = true
+			}
 		}
 
-		// Update existing posts
-		existingPosts = currentSlugs
+		// feedIndex accumulates across passes, so the feed/sitemap always
+		// cover every synced post, not just the posts fetched this pass.
+		feedEntries := make([]FeedEntry, 0, len(feedIndex))
+		for _, entry := range feedIndex {
+			feedEntries = append(feedEntries, entry)
+		}
+		if err := writeFeeds(feedEntries, feedCfg); err != nil {
+			log.Printf("Failed to write feed/sitemap: %v", err)
+		}
 
-		time.Sleep(1 * time.Second)
+		time.Sleep(flags.Interval)
 	}
-}
\ No newline at end of file
+}
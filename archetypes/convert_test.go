@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExpandShortcodes(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "code shortcode with language",
+			in:   `[code lang="go"]fmt.Println("hi")[/code]`,
+			want: `<pre><code class="language-go">fmt.Println("hi")</code></pre>`,
+		},
+		{
+			name: "code shortcode without language",
+			in:   `[code]plain text[/code]`,
+			want: `<pre><code class="language-">plain text</code></pre>`,
+		},
+		{
+			name: "caption shortcode",
+			in:   `[caption id="attachment_1"]<img src="https://example.com/cat.jpg" class="size-full">A cat[/caption]`,
+			want: `{{< figure src="https://example.com/cat.jpg" caption="A cat" >}}`,
+		},
+		{
+			name: "googlemaps shortcode",
+			in:   `[googlemaps https://maps.example.com/embed?pb=abc]`,
+			want: `{{< googlemaps https://maps.example.com/embed?pb=abc >}}`,
+		},
+		{
+			name: "latex shortcode",
+			in:   `$latex x^2 + y^2 = z^2$`,
+			want: `$x^2 + y^2 = z^2$`,
+		},
+		{
+			name: "double-escaped html entities",
+			in:   `Cats &amp;amp; dogs &#8220;rule&#8221;`,
+			want: `Cats &amp; dogs "rule"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := expandShortcodes(tc.in, "")
+			if got != tc.want {
+				t.Errorf("expandShortcodes(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestExpandShortcodesGalleryResolvesAttachments(t *testing.T) {
+	sources := map[string]string{
+		"1": "https://example.com/one.jpg",
+		"2": "https://example.com/two.jpg",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/wp-json/wp/v2/media/")
+		src, ok := sources[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(struct {
+			SourceURL string `json:"source_url"`
+		}{SourceURL: src})
+	}))
+	defer server.Close()
+
+	got := expandShortcodes(`[gallery ids="1,2,3"]`, server.URL)
+	want := fmt.Sprintf(`<div class="gallery-grid"><img class="gallery-image" src="%s"><img class="gallery-image" src="%s"></div>`,
+		sources["1"], sources["2"])
+	if got != want {
+		t.Errorf("expandShortcodes(gallery) = %q, want %q", got, want)
+	}
+}
+
+func TestConvertToMarkdown(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "heading and paragraph",
+			in:   "<h2>Title</h2><p>Some <strong>bold</strong> text.</p>",
+			want: "## Title\n\nSome **bold** text.\n",
+		},
+		{
+			name: "unordered list",
+			in:   "<ul><li>One</li><li>Two</li></ul>",
+			want: "- One\n- Two\n",
+		},
+		{
+			name: "ordered list",
+			in:   "<ol><li>First</li><li>Second</li></ol>",
+			want: "1. First\n2. Second\n",
+		},
+		{
+			name: "blockquote",
+			in:   "<blockquote>Said something</blockquote>",
+			want: "> Said something\n",
+		},
+		{
+			name: "fenced code block",
+			in:   `<pre><code class="language-go">fmt.Println("hi")</code></pre>`,
+			want: "```go\nfmt.Println(\"hi\")\n```\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := convertToMarkdown(tc.in, "")
+			if strings.TrimSpace(got) != strings.TrimSpace(tc.want) {
+				t.Errorf("convertToMarkdown(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// FeedEntry is the subset of a synced post needed to render the Atom feed
+// and sitemap.
+type FeedEntry struct {
+	Title       string
+	Slug        string
+	ContentHTML string
+	Published   time.Time
+	Updated     time.Time
+}
+
+// feedConfig holds the settings needed to compute feed/sitemap URLs; all of
+// it is overridable via the FEED_DOMAIN, FEED_START_DATE and FEED_OUTPUT_DIR
+// environment variables so the tool can run against any site.
+type feedConfig struct {
+	Domain    string
+	StartDate time.Time
+	OutputDir string
+}
+
+func loadFeedConfig() feedConfig {
+	domain := os.Getenv("FEED_DOMAIN")
+	if domain == "" {
+		domain = "animalsjunction.com"
+	}
+
+	startDate := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if raw := os.Getenv("FEED_START_DATE"); raw != "" {
+		if t, err := time.Parse("2006-01-02", raw); err == nil {
+			startDate = t
+		}
+	}
+
+	outputDir := os.Getenv("FEED_OUTPUT_DIR")
+	if outputDir == "" {
+		outputDir = "public"
+	}
+
+	return feedConfig{Domain: domain, StartDate: startDate, OutputDir: outputDir}
+}
+
+func parseWPDate(value string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Self    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Link      atomLink    `xml:"link"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",cdata"`
+}
+
+// writeAtomFeed writes atom.xml into cfg.OutputDir (public by default) covering
+// every synced post, newest update first.
+func writeAtomFeed(entries []FeedEntry, cfg feedConfig) error {
+	sorted := append([]FeedEntry(nil), entries...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Updated.After(sorted[j].Updated) })
+
+	feedURL := fmt.Sprintf("https://%s", cfg.Domain)
+	feed := atomFeed{
+		Xmlns:   "http://www.w3.org/2005/Atom",
+		ID:      fmt.Sprintf("tag:%s,%s:/", cfg.Domain, cfg.StartDate.Format("2006-01-02")),
+		Title:   cfg.Domain,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Self: atomLink{
+			Rel:  "self",
+			Type: "application/atom+xml",
+			Href: feedURL + "/atom.xml",
+		},
+	}
+
+	for _, entry := range sorted {
+		postURL := fmt.Sprintf("%s/posts/%s/", feedURL, entry.Slug)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        fmt.Sprintf("tag:%s,%s:/posts/%s", cfg.Domain, cfg.StartDate.Format("2006-01-02"), entry.Slug),
+			Title:     entry.Title,
+			Updated:   entry.Updated.Format(time.RFC3339),
+			Published: entry.Published.Format(time.RFC3339),
+			Link:      atomLink{Rel: "alternate", Type: "text/html", Href: postURL},
+			Content:   atomContent{Type: "html", Value: entry.ContentHTML},
+		})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal atom feed: %w", err)
+	}
+
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+
+	out := append([]byte(xml.Header), data...)
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "atom.xml"), out, 0644)
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc      string  `xml:"loc"`
+	LastMod  string  `xml:"lastmod"`
+	Priority float64 `xml:"priority"`
+}
+
+// writeSitemap writes public/sitemap.xml, weighting priority by how recently
+// each post was updated: anything touched in the last 30 days gets 1.0,
+// decaying down to a 0.3 floor for older posts.
+func writeSitemap(entries []FeedEntry, cfg feedConfig) error {
+	urlset := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+
+	now := time.Now()
+	for _, entry := range entries {
+		age := now.Sub(entry.Updated)
+		priority := 1.0 - ((age.Hours()/24-30)/30)*0.1
+		if priority < 0.3 {
+			priority = 0.3
+		}
+		if priority > 1.0 {
+			priority = 1.0
+		}
+
+		urlset.URLs = append(urlset.URLs, sitemapURL{
+			Loc:      fmt.Sprintf("https://%s/posts/%s/", cfg.Domain, entry.Slug),
+			LastMod:  entry.Updated.Format("2006-01-02"),
+			Priority: priority,
+		})
+	}
+
+	data, err := xml.MarshalIndent(urlset, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sitemap: %w", err)
+	}
+
+	if err := os.MkdirAll(cfg.OutputDir, 0755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+
+	out := append([]byte(xml.Header), data...)
+	return os.WriteFile(filepath.Join(cfg.OutputDir, "sitemap.xml"), out, 0644)
+}
+
+// writeFeeds renders both the Atom feed and sitemap for the given posts.
+func writeFeeds(entries []FeedEntry, cfg feedConfig) error {
+	if err := writeAtomFeed(entries, cfg); err != nil {
+		return err
+	}
+	return writeSitemap(entries, cfg)
+}
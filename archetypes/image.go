@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/chai2010/webp"
+	"github.com/disintegration/imaging"
+)
+
+// responsiveWidths are the variant widths generated for every raster image,
+// in addition to the full-size original.
+var responsiveWidths = []int{480, 960, 1600}
+
+// imagePathLocks guards concurrent writes to the same content-addressed
+// file by goroutines within one process. It is an in-process lock: separate
+// poller and webhook processes sharing static/images are not coordinated by it.
+var imagePathLocks sync.Map
+
+func lockImagePath(path string) func() {
+	lock, _ := imagePathLocks.LoadOrStore(path, &sync.Mutex{})
+	mu := lock.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+// ImageAsset describes the files produced for a single downloaded image:
+// the content-addressed original plus any resized/WebP variants.
+type ImageAsset struct {
+	Original string
+	Variants []ImageVariant
+	WebP     string
+}
+
+// ImageVariant is one resized rendition of an image.
+type ImageVariant struct {
+	Width int
+	Path  string
+}
+
+// extFromImageURL returns the lowercased file extension of imgURL's path,
+// ignoring any query string (WordPress/CDN image URLs routinely carry one,
+// e.g. "photo.jpg?resize=800,600"), defaulting to .jpg when none is found.
+func extFromImageURL(imgURL string) string {
+	u, err := url.Parse(imgURL)
+	if err != nil {
+		return ".jpg"
+	}
+	ext := strings.ToLower(path.Ext(u.Path))
+	if ext == "" {
+		return ".jpg"
+	}
+	return ext
+}
+
+// fetchAndHashImage downloads imgURL, hashing it as it streams to disk so
+// the resulting filename is content-addressed (static/images/<sha256>.<ext>)
+// and re-downloads of the same bytes are idempotent.
+func fetchAndHashImage(imgURL, baseDir string) (path, ext string, err error) {
+	resp, err := http.Get(imgURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unexpected status %d downloading %s", resp.StatusCode, imgURL)
+	}
+
+	ext = extFromImageURL(imgURL)
+
+	tmp, err := os.CreateTemp(baseDir, "dl-*")
+	if err != nil {
+		return "", "", fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hasher), resp.Body); err != nil {
+		return "", "", fmt.Errorf("stream image: %w", err)
+	}
+
+	hash := hex.EncodeToString(hasher.Sum(nil))
+	finalPath := filepath.Join(baseDir, hash+ext)
+
+	unlock := lockImagePath(finalPath)
+	defer unlock()
+
+	if _, err := os.Stat(finalPath); err == nil {
+		return finalPath, ext, nil
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+	out, err := os.Create(finalPath)
+	if err != nil {
+		return "", "", fmt.Errorf("create %s: %w", finalPath, err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, tmp); err != nil {
+		return "", "", fmt.Errorf("write %s: %w", finalPath, err)
+	}
+
+	return finalPath, ext, nil
+}
+
+// processRasterVariants produces the 480/960/1600px resizes plus a WebP
+// encoding of a JPEG/PNG original; widths not narrower than the original are
+// skipped. Non-raster formats (svg, gif, ...) are left untouched and return
+// no variants and an empty WebP path.
+func processRasterVariants(originalPath, ext string) ([]ImageVariant, string, error) {
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return nil, "", nil
+	}
+
+	src, err := imaging.Open(originalPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("open %s: %w", originalPath, err)
+	}
+
+	base := strings.TrimSuffix(originalPath, ext)
+
+	var variants []ImageVariant
+	for _, width := range responsiveWidths {
+		if src.Bounds().Dx() <= width {
+			continue
+		}
+		resized := imaging.Resize(src, width, 0, imaging.Lanczos)
+		variantPath := fmt.Sprintf("%s-%d%s", base, width, ext)
+
+		unlock := lockImagePath(variantPath)
+		err := imaging.Save(resized, variantPath)
+		unlock()
+		if err != nil {
+			return nil, "", fmt.Errorf("save variant %s: %w", variantPath, err)
+		}
+		variants = append(variants, ImageVariant{Width: width, Path: variantPath})
+	}
+
+	webpPath := base + ".webp"
+	unlock := lockImagePath(webpPath)
+	out, err := os.Create(webpPath)
+	if err == nil {
+		err = webp.Encode(out, src, &webp.Options{Quality: 80})
+		out.Close()
+	}
+	unlock()
+	if err != nil {
+		return variants, "", fmt.Errorf("encode webp %s: %w", webpPath, err)
+	}
+
+	return variants, webpPath, nil
+}
+
+// toPublicPath rewrites a filesystem path under static/images to the Hugo-served
+// "/images/..." URL.
+func toPublicPath(path string) string {
+	return "/images/" + filepath.Base(path)
+}
+
+// buildImageShortcode renders a Hugo figure/picture shortcode with a
+// srcset covering every generated variant plus lazy-loading.
+func buildImageShortcode(asset ImageAsset) string {
+	if len(asset.Variants) == 0 && asset.WebP == "" {
+		return fmt.Sprintf(`{{< figure src="%s" loading="lazy" >}}`, toPublicPath(asset.Original))
+	}
+
+	var srcset []string
+	for _, v := range asset.Variants {
+		srcset = append(srcset, fmt.Sprintf("%s %dw", toPublicPath(v.Path), v.Width))
+	}
+	srcset = append(srcset, toPublicPath(asset.Original))
+
+	webpAttr := ""
+	if asset.WebP != "" {
+		webpAttr = fmt.Sprintf(` webp="%s"`, toPublicPath(asset.WebP))
+	}
+
+	return fmt.Sprintf(`{{< picture src="%s" srcset="%s"%s loading="lazy" >}}`,
+		toPublicPath(asset.Original), strings.Join(srcset, ", "), webpAttr)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PollState tracks the HTTP caching metadata and last successful sync time
+// for the poller, persisted to disk so it survives restarts and can be
+// shared across pages of a paginated fetch. mu guards ETags/LastModified,
+// since fetchPostsSince fetches pages concurrently.
+type PollState struct {
+	ETags        map[string]string `json:"etags"`
+	LastModified map[string]string `json:"last_modified"`
+	LastSync     string            `json:"last_sync"`
+
+	mu sync.Mutex
+}
+
+const pollStatePath = "wp-poll-state.json"
+
+func loadPollState(path string) *PollState {
+	state := &PollState{ETags: make(map[string]string), LastModified: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return state
+	}
+	return state
+}
+
+func savePollState(path string, state *PollState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// doWithBackoff issues req, retrying with exponential backoff plus jitter on
+// 5xx/429 responses. A Retry-After header given in seconds takes priority
+// over the computed backoff; the HTTP-date form is ignored.
+func doWithBackoff(req *http.Request, maxAttempts int) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			lastErr = err
+		} else if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			wait := backoffDelay(attempt)
+			if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+				if secs, err := strconv.Atoi(retryAfter); err == nil {
+					wait = time.Duration(secs) * time.Second
+				}
+			}
+			resp.Body.Close()
+			lastErr = fmt.Errorf("status %d", resp.StatusCode)
+			time.Sleep(wait)
+			continue
+		} else {
+			return resp, nil
+		}
+		time.Sleep(backoffDelay(attempt))
+	}
+	return nil, fmt.Errorf("giving up after %d attempts: %w", maxAttempts, lastErr)
+}
+
+func backoffDelay(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt)) * time.Second
+	jitter := time.Duration(rand.Int63n(int64(base) / 2))
+	return base + jitter
+}
+
+// fetchPostsPage fetches a single page of apiURL, sending conditional GET
+// headers from state and requesting only posts modified after
+// state.LastSync. A 304 response is treated as "no work" for that page and
+// yields a nil posts slice, but totalPages is still read off the response
+// (304 included) so a cache hit on one page never stalls pagination of the
+// rest. Conditional GET validators are cached per full page URL, not just
+// page number, so a validator cached for one modified_after query is never
+// sent with another. Safe for concurrent use across pages of the same state.
+func fetchPostsPage(apiURL string, state *PollState, page int) (posts []Post, totalPages int, err error) {
+	pageURL := fmt.Sprintf("%s?per_page=100&page=%d&orderby=modified&order=desc", apiURL, page)
+	if state.LastSync != "" {
+		pageURL += "&modified_after=" + state.LastSync
+	}
+
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	state.mu.Lock()
+	etag, hasEtag := state.ETags[pageURL]
+	lastMod, hasLastMod := state.LastModified[pageURL]
+	state.mu.Unlock()
+	if hasEtag {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if hasLastMod {
+		req.Header.Set("If-Modified-Since", lastMod)
+	}
+
+	resp, err := doWithBackoff(req, 5)
+	if err != nil {
+		return nil, 0, fmt.Errorf("fetching page %d: %w", page, err)
+	}
+
+	totalPages = 1
+	if totalHeader := resp.Header.Get("X-WP-TotalPages"); totalHeader != "" {
+		if n, err := strconv.Atoi(totalHeader); err == nil {
+			totalPages = n
+		}
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		return nil, totalPages, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("unexpected status %d fetching page %d", resp.StatusCode, page)
+	}
+
+	state.mu.Lock()
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		state.ETags[pageURL] = etag
+	}
+	if lastMod := resp.Header.Get("Last-Modified"); lastMod != "" {
+		state.LastModified[pageURL] = lastMod
+	}
+	state.mu.Unlock()
+
+	err = json.NewDecoder(resp.Body).Decode(&posts)
+	resp.Body.Close()
+	if err != nil {
+		return nil, 0, fmt.Errorf("decoding page %d: %w", page, err)
+	}
+
+	return posts, totalPages, nil
+}
+
+// fetchPostsSince walks every page of apiURL. Page 1 is fetched first to
+// learn totalPages, then any remaining pages are fetched concurrently
+// across up to concurrency workers, bounding how hard a poll hits the
+// origin. Pages are reassembled in page order regardless of completion
+// order, so the combined result matches what a sequential fetch would have
+// produced.
+func fetchPostsSince(apiURL string, state *PollState, concurrency int) ([]Post, error) {
+	firstPosts, totalPages, err := fetchPostsPage(apiURL, state, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	pagePosts := make([][]Post, totalPages+1)
+	pagePosts[1] = firstPosts
+
+	if totalPages > 1 {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+
+		pages := make(chan int)
+		errs := make(chan error, totalPages-1)
+		var mu sync.Mutex
+
+		var wg sync.WaitGroup
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for page := range pages {
+					posts, _, err := fetchPostsPage(apiURL, state, page)
+					if err != nil {
+						errs <- err
+						continue
+					}
+					mu.Lock()
+					pagePosts[page] = posts
+					mu.Unlock()
+				}
+			}()
+		}
+
+		for page := 2; page <= totalPages; page++ {
+			pages <- page
+		}
+		close(pages)
+		wg.Wait()
+		close(errs)
+
+		if err := <-errs; err != nil {
+			return nil, err
+		}
+	}
+
+	var allPosts []Post
+	for page := 1; page <= totalPages; page++ {
+		allPosts = append(allPosts, pagePosts[page]...)
+	}
+
+	state.LastSync = time.Now().UTC().Format("2006-01-02T15:04:05")
+	return allPosts, nil
+}
+
+// pollerFlags are the poll interval and fetch concurrency, overridable via
+// flags so operators can tune how hard the poller hits the origin.
+type pollerFlags struct {
+	Interval    time.Duration
+	Concurrency int
+}
+
+func parsePollerFlags() pollerFlags {
+	interval := flag.Duration("poll-interval", 4*time.Second, "how often to poll the WordPress REST API")
+	concurrency := flag.Int("poll-concurrency", 4, "max concurrent page fetches per poll")
+	flag.Parse()
+	return pollerFlags{Interval: *interval, Concurrency: *concurrency}
+}
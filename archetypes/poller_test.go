@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestFetchPostsSincePaginatesPast304 reproduces a poll where page 1 of a
+// two-page result 304s against a previously cached ETag: pagination must
+// still continue to page 2 instead of stalling because X-WP-TotalPages was
+// never read off the 304 response.
+func TestFetchPostsSincePaginatesPast304(t *testing.T) {
+	const etag = `"page-1-etag"`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-WP-TotalPages", "2")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			json.NewEncoder(w).Encode([]Post{{Slug: "page-one-post"}})
+		case "2":
+			json.NewEncoder(w).Encode([]Post{{Slug: "page-two-post"}})
+		default:
+			t.Fatalf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	page1URL := fmt.Sprintf("%s?per_page=100&page=1&orderby=modified&order=desc", server.URL)
+	state := &PollState{
+		ETags:        map[string]string{page1URL: etag},
+		LastModified: map[string]string{},
+	}
+
+	posts, err := fetchPostsSince(server.URL, state, 1)
+	if err != nil {
+		t.Fatalf("fetchPostsSince: %v", err)
+	}
+	if len(posts) != 1 || posts[0].Slug != "page-two-post" {
+		t.Fatalf("got %+v, want only page-two-post (page 1's 304 should not stall pagination)", posts)
+	}
+}
+
+// TestFetchPostsSinceIgnoresStaleEtagAcrossQueries ensures a validator
+// cached for one page URL is never sent with a different query (e.g. once
+// modified_after changes between polls), since the server's ETag describes
+// the response to that exact request, not the page number alone.
+func TestFetchPostsSinceIgnoresStaleEtagAcrossQueries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-WP-TotalPages", "1")
+		if r.Header.Get("If-None-Match") != "" {
+			t.Fatalf("unexpected If-None-Match sent for a query never seen before: %q", r.Header.Get("If-None-Match"))
+		}
+		w.Header().Set("ETag", `"full-pass-etag"`)
+		json.NewEncoder(w).Encode([]Post{{Slug: "a-post"}})
+	}))
+	defer server.Close()
+
+	state := &PollState{ETags: map[string]string{}, LastModified: map[string]string{}}
+
+	if _, err := fetchPostsSince(server.URL, state, 1); err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	state.LastSync = "2026-01-01T00:00:00"
+
+	if _, err := fetchPostsSince(server.URL, state, 1); err != nil {
+		t.Fatalf("second fetch: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// TaxonomyTerm is the resolved human-readable form of a WordPress term ID.
+type TaxonomyTerm struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// TaxonomyCache maps WordPress category/tag IDs to their resolved name and
+// slug. It is persisted as JSON on disk so the poller and webhook binaries
+// can share it without a second round-trip to the WordPress REST API.
+type TaxonomyCache struct {
+	Categories map[int]TaxonomyTerm `json:"categories"`
+	Tags       map[int]TaxonomyTerm `json:"tags"`
+	ETags      map[string]string    `json:"etags"`
+}
+
+const taxonomyCachePath = "wp-taxonomy-cache.json"
+
+// taxonomyDisplayField chooses whether front matter emits the resolved
+// "name" or "slug" for categories/tags. Configurable via WP_TAXONOMY_FIELD.
+func taxonomyDisplayField() string {
+	if field := os.Getenv("WP_TAXONOMY_FIELD"); field == "slug" {
+		return "slug"
+	}
+	return "name"
+}
+
+func loadTaxonomyCache(path string) *TaxonomyCache {
+	cache := &TaxonomyCache{
+		Categories: make(map[int]TaxonomyTerm),
+		Tags:       make(map[int]TaxonomyTerm),
+		ETags:      make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return cache
+	}
+	return cache
+}
+
+func saveTaxonomyCache(path string, cache *TaxonomyCache) error {
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// refreshTaxonomy walks the paginated /wp-json/wp/v2/{categories,tags}
+// endpoints and merges any new or changed terms into the cache. Pages are
+// skipped with a conditional GET when the cached ETag still matches; a 404
+// on the first page drops that taxonomy's cached entries entirely.
+func refreshTaxonomy(siteURL string, cache *TaxonomyCache) error {
+	categories, err := fetchTaxonomyTerms(siteURL, "categories", cache, cache.Categories)
+	if err != nil {
+		return fmt.Errorf("refresh categories: %w", err)
+	}
+	cache.Categories = categories
+
+	tags, err := fetchTaxonomyTerms(siteURL, "tags", cache, cache.Tags)
+	if err != nil {
+		return fmt.Errorf("refresh tags: %w", err)
+	}
+	cache.Tags = tags
+
+	return nil
+}
+
+// fetchTaxonomyTerms walks the paginated endpoint, seeded with the
+// previously resolved terms so that a 304 page (whose body is never
+// decoded) keeps its cached terms instead of dropping them from the result.
+func fetchTaxonomyTerms(siteURL, endpoint string, cache *TaxonomyCache, existing map[int]TaxonomyTerm) (map[int]TaxonomyTerm, error) {
+	terms := make(map[int]TaxonomyTerm, len(existing))
+	for id, term := range existing {
+		terms[id] = term
+	}
+	page := 1
+	totalPages := 1
+
+	for page <= totalPages {
+		url := fmt.Sprintf("%s/wp-json/wp/v2/%s?per_page=100&page=%d", siteURL, endpoint, page)
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		cacheKey := fmt.Sprintf("%s:%d", endpoint, page)
+		if etag, ok := cache.ETags[cacheKey]; ok {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if totalHeader := resp.Header.Get("X-WP-TotalPages"); totalHeader != "" {
+			if n, err := strconv.Atoi(totalHeader); err == nil {
+				totalPages = n
+			}
+		}
+
+		if resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			page++
+			continue
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			delete(cache.ETags, cacheKey)
+			if page == 1 {
+				return make(map[int]TaxonomyTerm), nil
+			}
+			return terms, nil
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, fmt.Errorf("unexpected status %d fetching %s", resp.StatusCode, url)
+		}
+
+		if etag := resp.Header.Get("ETag"); etag != "" {
+			cache.ETags[cacheKey] = etag
+		}
+
+		var pageTerms []struct {
+			ID   int    `json:"id"`
+			Name string `json:"name"`
+			Slug string `json:"slug"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&pageTerms)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, t := range pageTerms {
+			terms[t.ID] = TaxonomyTerm{Name: t.Name, Slug: t.Slug}
+		}
+		page++
+	}
+
+	return terms, nil
+}
+
+// resolveTerms maps a list of WordPress term IDs to display strings using
+// the configured taxonomy field, falling back to the numeric ID when a term
+// hasn't been resolved yet.
+func resolveTerms(ids []int, resolved map[int]TaxonomyTerm) []string {
+	field := taxonomyDisplayField()
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		term, ok := resolved[id]
+		if !ok {
+			names = append(names, strconv.Itoa(id))
+			continue
+		}
+		if field == "slug" {
+			names = append(names, term.Slug)
+		} else {
+			names = append(names, term.Name)
+		}
+	}
+	return names
+}
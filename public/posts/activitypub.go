@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ASTag is an ActivityStreams Hashtag attached to a post for each resolved
+// category/tag.
+type ASTag struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Href string `json:"href"`
+}
+
+// ASAttachment is an ActivityStreams Image attachment for an inline image.
+type ASAttachment struct {
+	Type      string `json:"type"`
+	URL       string `json:"url"`
+	MediaType string `json:"mediaType,omitempty"`
+}
+
+// ASObject is the ActivityStreams document written alongside each post so
+// Fediverse servers can consume it directly.
+type ASObject struct {
+	Context      []string       `json:"@context"`
+	Type         string         `json:"type"`
+	ID           string         `json:"id"`
+	URL          string         `json:"url"`
+	AttributedTo string         `json:"attributedTo"`
+	To           []string       `json:"to"`
+	Published    string         `json:"published,omitempty"`
+	Updated      string         `json:"updated,omitempty"`
+	Name         string         `json:"name,omitempty"`
+	Content      string         `json:"content"`
+	Tag          []ASTag        `json:"tag,omitempty"`
+	Attachment   []ASAttachment `json:"attachment,omitempty"`
+}
+
+func activityPubActorURL() string {
+	if actor := os.Getenv("ACTIVITYPUB_ACTOR_URL"); actor != "" {
+		return actor
+	}
+	return wpSiteURL + "/actor"
+}
+
+var asImgSrcRegex = regexp.MustCompile(`<img\s+[^>]*src=["']([^"']+)["'][^>]*>`)
+
+func discoverImages(contentHTML string) []string {
+	matches := asImgSrcRegex.FindAllStringSubmatch(contentHTML, -1)
+	urls := make([]string, 0, len(matches))
+	for _, m := range matches {
+		urls = append(urls, m[1])
+	}
+	return urls
+}
+
+// buildActivityStreamsObject assembles the ActivityStreams document for a
+// single post: an Article when it has a title, otherwise a Note.
+func buildActivityStreamsObject(title, postURL, contentHTML, published, updated string, tags []string) ASObject {
+	objType := "Note"
+	if strings.TrimSpace(title) != "" {
+		objType = "Article"
+	}
+
+	asTags := make([]ASTag, 0, len(tags))
+	for _, tag := range tags {
+		asTags = append(asTags, ASTag{Type: "Hashtag", Name: "#" + tag, Href: postURL})
+	}
+
+	attachments := make([]ASAttachment, 0)
+	for _, img := range discoverImages(contentHTML) {
+		attachments = append(attachments, ASAttachment{Type: "Image", URL: img})
+	}
+
+	return ASObject{
+		Context:      []string{"https://www.w3.org/ns/activitystreams"},
+		Type:         objType,
+		ID:           postURL,
+		URL:          postURL,
+		AttributedTo: activityPubActorURL(),
+		To:           []string{"https://www.w3.org/ns/activitystreams#Public"},
+		Published:    published,
+		Updated:      updated,
+		Name:         title,
+		Content:      contentHTML,
+		Tag:          asTags,
+		Attachment:   attachments,
+	}
+}
+
+// writeActivityStreamsSibling writes the post's ActivityStreams document to
+// <outputDir>/<slug>.as.json, next to its Markdown file.
+func writeActivityStreamsSibling(outputDir, slug string, obj ASObject) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal activitystreams object: %w", err)
+	}
+	return os.WriteFile(filepath.Join(outputDir, slug+".as.json"), data, 0644)
+}
+
+// handlePostActivity serves a post's ActivityStreams document, either at the
+// explicit /posts/<slug>.as path or at the bare post URL when the client
+// negotiates Accept: application/activity+json.
+func handlePostActivity(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	isActivityPath := strings.HasSuffix(path, ".as")
+	if !isActivityPath && !strings.Contains(r.Header.Get("Accept"), "application/activity+json") {
+		http.NotFound(w, r)
+		return
+	}
+
+	slug := strings.TrimSuffix(strings.TrimPrefix(path, "/posts/"), ".as")
+	data, err := os.ReadFile(filepath.Join("content/posts", sanitizeFilename(slug)+".as.json"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/activity+json")
+	w.Write(data)
+}
+
+// personActor is the static ActivityPub actor document advertised for the
+// whole site so Fediverse servers can follow it.
+type personActor struct {
+	Context           []string `json:"@context"`
+	Type              string   `json:"type"`
+	ID                string   `json:"id"`
+	PreferredUsername string   `json:"preferredUsername"`
+	Inbox             string   `json:"inbox"`
+	Outbox            string   `json:"outbox"`
+}
+
+func handleActor(w http.ResponseWriter, r *http.Request) {
+	actor := personActor{
+		Context:           []string{"https://www.w3.org/ns/activitystreams"},
+		Type:              "Person",
+		ID:                activityPubActorURL(),
+		PreferredUsername: strings.TrimPrefix(wpSiteURL, "https://"),
+		Inbox:             wpSiteURL + "/inbox",
+		Outbox:            wpSiteURL + "/outbox",
+	}
+	w.Header().Set("Content-Type", "application/activity+json")
+	json.NewEncoder(w).Encode(actor)
+}
+
+// orderedCollection is an ActivityStreams OrderedCollection, used here for
+// the site's outbox of recent posts.
+type orderedCollection struct {
+	Context      []string      `json:"@context"`
+	Type         string        `json:"type"`
+	ID           string        `json:"id"`
+	TotalItems   int           `json:"totalItems"`
+	OrderedItems []interface{} `json:"orderedItems"`
+}
+
+// handleOutbox lists every post's ActivityStreams object found in
+// content/posts as an OrderedCollection, in filename order; it is neither
+// limited to recent posts nor sorted by date.
+func handleOutbox(w http.ResponseWriter, r *http.Request) {
+	entries, err := os.ReadDir("content/posts")
+	if err != nil {
+		http.Error(w, "Failed to read posts", http.StatusInternalServerError)
+		return
+	}
+
+	var items []interface{}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".as.json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join("content/posts", entry.Name()))
+		if err != nil {
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			continue
+		}
+		items = append(items, obj)
+	}
+
+	collection := orderedCollection{
+		Context:      []string{"https://www.w3.org/ns/activitystreams"},
+		Type:         "OrderedCollection",
+		ID:           wpSiteURL + "/outbox",
+		TotalItems:   len(items),
+		OrderedItems: items,
+	}
+	w.Header().Set("Content-Type", "application/activity+json")
+	json.NewEncoder(w).Encode(collection)
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// micropubTokenEndpoint is the IndieAuth token endpoint used to verify
+// bearer tokens presented to the Micropub endpoint.
+func micropubTokenEndpoint() string {
+	if endpoint := os.Getenv("MICROPUB_TOKEN_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	return wpSiteURL + "/wp-json/indieauth/token"
+}
+
+// indieAuthIdentity is what the token endpoint reports about a verified
+// bearer token.
+type indieAuthIdentity struct {
+	Me       string `json:"me"`
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// verifyBearerToken checks the request's Authorization header against the
+// configured IndieAuth token endpoint and returns the verified identity.
+func verifyBearerToken(r *http.Request) (*indieAuthIdentity, error) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		return nil, fmt.Errorf("missing bearer token")
+	}
+	token := strings.TrimPrefix(auth, "Bearer ")
+
+	req, err := http.NewRequest(http.MethodGet, micropubTokenEndpoint(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("contacting token endpoint: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint rejected token: %d", resp.StatusCode)
+	}
+
+	var identity indieAuthIdentity
+	if err := json.NewDecoder(resp.Body).Decode(&identity); err != nil {
+		return nil, fmt.Errorf("decoding token endpoint response: %w", err)
+	}
+	if identity.Me == "" {
+		return nil, fmt.Errorf("token endpoint did not return a verified identity")
+	}
+	return &identity, nil
+}
+
+// micropubEntry is the normalized set of h=entry properties accepted from
+// any of the three Micropub request encodings.
+type micropubEntry struct {
+	Content     string
+	Name        string
+	Categories  []string
+	Published   string
+	Slug        string
+	InReplyTo   string
+	LikeOf      string
+	BookmarkOf  string
+	PhotoURLs   []string
+	PhotoUpload []*multipart.FileHeader
+}
+
+// micropubEntryType infers the Hugo `type` front matter value from which
+// Micropub properties are present, per the usual IndieWeb post-type rules.
+func (e micropubEntry) micropubEntryType() string {
+	switch {
+	case e.LikeOf != "":
+		return "like"
+	case e.BookmarkOf != "":
+		return "bookmark"
+	case e.InReplyTo != "":
+		return "reply"
+	case e.Name != "":
+		return "article"
+	default:
+		return "note"
+	}
+}
+
+func parseMicropubEntry(r *http.Request) (*micropubEntry, error) {
+	contentType := r.Header.Get("Content-Type")
+
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		var body struct {
+			Type       []string            `json:"type"`
+			Properties map[string][]string `json:"properties"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, fmt.Errorf("decoding JSON body: %w", err)
+		}
+		get := func(key string) string {
+			if v := body.Properties[key]; len(v) > 0 {
+				return v[0]
+			}
+			return ""
+		}
+		return &micropubEntry{
+			Content:    get("content"),
+			Name:       get("name"),
+			Categories: body.Properties["category"],
+			Published:  get("published"),
+			Slug:       get("mp-slug"),
+			InReplyTo:  get("in-reply-to"),
+			LikeOf:     get("like-of"),
+			BookmarkOf: get("bookmark-of"),
+		}, nil
+
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, fmt.Errorf("parsing multipart form: %w", err)
+		}
+		entry := &micropubEntry{
+			Content:    r.FormValue("content"),
+			Name:       r.FormValue("name"),
+			Categories: r.MultipartForm.Value["category[]"],
+			Published:  r.FormValue("published"),
+			Slug:       r.FormValue("mp-slug"),
+			InReplyTo:  r.FormValue("in-reply-to"),
+			LikeOf:     r.FormValue("like-of"),
+			BookmarkOf: r.FormValue("bookmark-of"),
+		}
+		if r.MultipartForm != nil {
+			entry.PhotoUpload = r.MultipartForm.File["photo"]
+		}
+		return entry, nil
+
+	default: // application/x-www-form-urlencoded
+		if err := r.ParseForm(); err != nil {
+			return nil, fmt.Errorf("parsing form: %w", err)
+		}
+		return &micropubEntry{
+			Content:    r.FormValue("content"),
+			Name:       r.FormValue("name"),
+			Categories: r.Form["category[]"],
+			Published:  r.FormValue("published"),
+			Slug:       r.FormValue("mp-slug"),
+			InReplyTo:  r.FormValue("in-reply-to"),
+			LikeOf:     r.FormValue("like-of"),
+			BookmarkOf: r.FormValue("bookmark-of"),
+		}, nil
+	}
+}
+
+// saveUploadedPhoto feeds a Micropub photo upload through the same
+// content-addressed image pipeline used for synced posts, so re-uploading
+// the same photo twice dedupes onto the same static/images/<sha256>.<ext>
+// file instead of producing a new file each time.
+func saveUploadedPhoto(header *multipart.FileHeader) (string, error) {
+	file, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	baseDir := filepath.Join("static", "images")
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return "", err
+	}
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if ext == "" {
+		ext = ".jpg"
+	}
+
+	hashed, err := hashAndStoreImage(file, baseDir, ext)
+	if err != nil {
+		return "", err
+	}
+
+	variants, webpPath, err := processRasterVariants(hashed, ext)
+	if err != nil {
+		log.Printf("Photo variant generation failed for %s: %v", header.Filename, err)
+	}
+
+	return buildImageShortcode(ImageAsset{Original: hashed, Variants: variants, WebP: webpPath}), nil
+}
+
+func micropubSlug(entry *micropubEntry) string {
+	if entry.Slug != "" {
+		return sanitizeFilename(entry.Slug)
+	}
+	if entry.Name != "" {
+		return sanitizeFilename(entry.Name)
+	}
+	return strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// entryToFrontMatter renders a Micropub entry as a Hugo post, mirroring the
+// supplied property set into front matter.
+func entryToFrontMatter(entry *micropubEntry, slug string) string {
+	published := entry.Published
+	if published == "" {
+		published = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	title := entry.Name
+	if title == "" {
+		title = entry.Content
+		if len(title) > 60 {
+			title = title[:60]
+		}
+	}
+
+	fm := "---\n"
+	fm += fmt.Sprintf("title: %q\n", title)
+	fm += fmt.Sprintf("date: %s\n", published)
+	fm += fmt.Sprintf("slug: %q\n", slug)
+	fm += fmt.Sprintf("type: %s\n", entry.micropubEntryType())
+	fm += "draft: false\n"
+	if len(entry.Categories) > 0 {
+		fm += "tags:\n"
+		for _, cat := range entry.Categories {
+			fm += fmt.Sprintf("  - %q\n", cat)
+		}
+	}
+	if entry.InReplyTo != "" {
+		fm += fmt.Sprintf("in_reply_to: %q\n", entry.InReplyTo)
+	}
+	if entry.LikeOf != "" {
+		fm += fmt.Sprintf("like_of: %q\n", entry.LikeOf)
+	}
+	if entry.BookmarkOf != "" {
+		fm += fmt.Sprintf("bookmark_of: %q\n", entry.BookmarkOf)
+	}
+	fm += "---\n\n"
+
+	return fm + entry.Content + "\n"
+}
+
+// rebuildDebouncer coalesces bursts of Micropub writes into a single `hugo`
+// invocation, firing once no new post has landed for the quiet period.
+type rebuildDebouncer struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	quiet time.Duration
+}
+
+var micropubRebuilder = &rebuildDebouncer{quiet: 2 * time.Second}
+
+func (d *rebuildDebouncer) trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.timer = time.AfterFunc(d.quiet, func() {
+		cmd := rebuildHugoCommand()
+		if err := cmd.Run(); err != nil {
+			log.Printf("Debounced Hugo rebuild failed: %v", err)
+		} else {
+			log.Println("Debounced Hugo rebuild complete")
+		}
+	})
+}
+
+func handleMicropubQuery(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("q") {
+	case "config":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"media-endpoint": "/micropub/media",
+		})
+	case "syndicate-to":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"syndicate-to": []interface{}{},
+		})
+	case "source":
+		url := r.URL.Query().Get("url")
+		slug := sanitizeFilename(strings.TrimSuffix(filepath.Base(url), filepath.Ext(url)))
+		data, err := os.ReadFile(filepath.Join("content/posts", slug+".md"))
+		if err != nil {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	default:
+		http.Error(w, "Unsupported query", http.StatusBadRequest)
+	}
+}
+
+func handleMicropub(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		handleMicropubQuery(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := verifyBearerToken(r); err != nil {
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	entry, err := parseMicropubEntry(r)
+	if err != nil {
+		http.Error(w, "Failed to parse entry: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, photo := range entry.PhotoUpload {
+		shortcode, err := saveUploadedPhoto(photo)
+		if err != nil {
+			log.Printf("Failed to save uploaded photo %s: %v", photo.Filename, err)
+			continue
+		}
+		entry.Content += "\n\n" + shortcode
+	}
+
+	slug := micropubSlug(entry)
+	outputDir := "content/posts"
+	if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+		http.Error(w, "Failed to create content directory", http.StatusInternalServerError)
+		return
+	}
+
+	filename := filepath.Join(outputDir, slug+".md")
+	if err := os.WriteFile(filename, []byte(entryToFrontMatter(entry, slug)), 0644); err != nil {
+		http.Error(w, "Failed to write post", http.StatusInternalServerError)
+		return
+	}
+
+	location := fmt.Sprintf("%s/posts/%s/", wpSiteURL, slug)
+	asObject := buildActivityStreamsObject(entry.Name, location, entry.Content, entry.Published, entry.Published, entry.Categories)
+	if err := writeActivityStreamsSibling(outputDir, slug, asObject); err != nil {
+		log.Printf("Failed to write ActivityStreams document for %s: %v", slug, err)
+	}
+
+	micropubRebuilder.trigger()
+
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusCreated)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -9,8 +10,13 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
 	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
 )
 
 // Post structure for incoming webhook data
@@ -21,12 +27,25 @@ type Post struct {
 	Content struct {
 		Rendered string `json:"rendered"`
 	} `json:"content"`
-	Date     string `json:"date"`
-	Modified string `json:"modified"`
-	Slug     string `json:"slug"`
-	Status   string `json:"status"`
+	Date       string `json:"date"`
+	Modified   string `json:"modified"`
+	Slug       string `json:"slug"`
+	Status     string `json:"status"`
+	Tags       []int  `json:"tags"`
+	Categories []int  `json:"categories"`
 }
 
+// wpSiteURL is the WordPress origin used to resolve category/tag IDs.
+const wpSiteURL = "https://animalsjunction.com"
+
+var taxonomy = loadTaxonomyCache(taxonomyCachePath)
+
+// taxonomyMu guards taxonomy: net/http serves each request on its own
+// goroutine, and refreshTaxonomy mutates the cache's maps in place, so two
+// webhook deliveries racing on it would otherwise crash the process with a
+// concurrent map write.
+var taxonomyMu sync.Mutex
+
 // sanitizeFilename removes special characters from filenames
 func sanitizeFilename(name string) string {
 	name = strings.ToLower(strings.TrimSpace(name))
@@ -38,19 +57,199 @@ func sanitizeFilename(name string) string {
 	return name
 }
 
-// convertHTMLToMarkdown converts HTML to Markdown
+var (
+	shortcodeCodeRegex    = regexp.MustCompile(`(?s)\[code(?:\s+lang=["']?([a-zA-Z0-9_+-]*)["']?)?\](.*?)\[/code\]`)
+	shortcodeCaptionRegex = regexp.MustCompile(`(?s)\[caption[^\]]*\](.*?)<img([^>]*)>(.*?)\[/caption\]`)
+	shortcodeGalleryRegex = regexp.MustCompile(`\[gallery\s+ids=["']([0-9,\s]+)["'][^\]]*\]`)
+	shortcodeMapsRegex    = regexp.MustCompile(`\[googlemaps\s+([^\]]+)\]`)
+	latexRegex            = regexp.MustCompile(`\$latex\s+(.*?)\$`)
+	htmlEntityReplacer    = strings.NewReplacer(
+		"&amp;", "&",
+		"&gt;", ">",
+		"&lt;", "<",
+		"&#8220;", "\"",
+		"&#8221;", "\"",
+		"&#8216;", "'",
+		"&#8217;", "'",
+		"&nbsp;", " ",
+	)
+)
+
+// fetchMediaURL resolves a WordPress attachment ID to its source image URL
+// via /wp-json/wp/v2/media/<id>.
+func fetchMediaURL(siteURL, mediaID string) (string, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/wp-json/wp/v2/media/%s", siteURL, mediaID))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d fetching media %s", resp.StatusCode, mediaID)
+	}
+
+	var media struct {
+		SourceURL string `json:"source_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&media); err != nil {
+		return "", err
+	}
+	return media.SourceURL, nil
+}
+
+// expandShortcodes rewrites WordPress classic-editor shortcodes into the
+// HTML/Hugo-shortcode equivalents goquery/goldmark expect to see.
+func expandShortcodes(html string) string {
+	html = htmlEntityReplacer.Replace(html)
+
+	html = shortcodeCodeRegex.ReplaceAllString(html, "<pre><code class=\"language-$1\">$2</code></pre>")
+
+	html = shortcodeCaptionRegex.ReplaceAllStringFunc(html, func(match string) string {
+		parts := shortcodeCaptionRegex.FindStringSubmatch(match)
+		attrs := parts[2]
+		src := ""
+		if m := regexp.MustCompile(`src=["']([^"']+)["']`).FindStringSubmatch(attrs); m != nil {
+			src = m[1]
+		}
+		caption := strings.TrimSpace(stripTags(parts[3]))
+		return fmt.Sprintf(`{{< figure src="%s" caption="%s" >}}`, src, caption)
+	})
+
+	html = shortcodeGalleryRegex.ReplaceAllStringFunc(html, func(match string) string {
+		ids := shortcodeGalleryRegex.FindStringSubmatch(match)[1]
+		var grid strings.Builder
+		grid.WriteString(`<div class="gallery-grid">`)
+		for _, id := range strings.Split(ids, ",") {
+			id = strings.TrimSpace(id)
+			if id == "" {
+				continue
+			}
+			src, err := fetchMediaURL(wpSiteURL, id)
+			if err != nil || src == "" {
+				log.Printf("Gallery attachment %s could not be resolved: %v", id, err)
+				continue
+			}
+			grid.WriteString(fmt.Sprintf(`<img class="gallery-image" src="%s">`, src))
+		}
+		grid.WriteString("</div>")
+		return grid.String()
+	})
+
+	html = shortcodeMapsRegex.ReplaceAllString(html, `{{< googlemaps $1 >}}`)
+
+	html = latexRegex.ReplaceAllString(html, "$$$1$$")
+
+	return html
+}
+
+func stripTags(s string) string {
+	return regexp.MustCompile(`<[^>]*>`).ReplaceAllString(s, "")
+}
+
+// convertHTMLToMarkdown parses the post HTML with goquery, walks the DOM to
+// build Markdown, and verifies the result by rendering it back through
+// goldmark before it is written to disk.
 func convertHTMLToMarkdown(html string) string {
-	markdown := html
-	markdown = regexp.MustCompile(`<h1[^>]*>(.*?)</h1>`).ReplaceAllString(markdown, "# $1\n")
-	markdown = regexp.MustCompile(`<h2[^>]*>(.*?)</h2>`).ReplaceAllString(markdown, "## $1\n")
-	markdown = regexp.MustCompile(`<h3[^>]*>(.*?)</h3>`).ReplaceAllString(markdown, "### $1\n")
-	markdown = regexp.MustCompile(`<p[^>]*>(.*?)</p>`).ReplaceAllString(markdown, "$1\n\n")
-	markdown = regexp.MustCompile(`<strong>(.*?)</strong>`).ReplaceAllString(markdown, "**$1**")
-	markdown = regexp.MustCompile(`<em>(.*?)</em>`).ReplaceAllString(markdown, "*$1*")
+	html = expandShortcodes(html)
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		log.Printf("Failed to parse post HTML: %v", err)
+		return html
+	}
+
+	var buf strings.Builder
+	walkNodes(doc.Contents(), &buf)
+	markdown := strings.TrimSpace(buf.String()) + "\n"
+
+	var rendered bytes.Buffer
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := md.Convert([]byte(markdown), &rendered); err != nil {
+		log.Printf("Markdown verification render failed: %v", err)
+	}
+
 	return markdown
 }
 
-// createHugoContent generates Hugo Markdown content with front matter
+// walkNodes recursively renders a goquery selection into Markdown.
+func walkNodes(sel *goquery.Selection, buf *strings.Builder) {
+	sel.Each(func(_ int, node *goquery.Selection) {
+		switch goquery.NodeName(node) {
+		case "h1":
+			buf.WriteString("# " + strings.TrimSpace(node.Text()) + "\n\n")
+		case "h2":
+			buf.WriteString("## " + strings.TrimSpace(node.Text()) + "\n\n")
+		case "h3":
+			buf.WriteString("### " + strings.TrimSpace(node.Text()) + "\n\n")
+		case "p":
+			var inline strings.Builder
+			walkNodes(node.Contents(), &inline)
+			buf.WriteString(strings.TrimSpace(inline.String()) + "\n\n")
+		case "strong", "b":
+			buf.WriteString("**" + strings.TrimSpace(node.Text()) + "**")
+		case "em", "i":
+			buf.WriteString("*" + strings.TrimSpace(node.Text()) + "*")
+		case "blockquote":
+			for _, line := range strings.Split(strings.TrimSpace(node.Text()), "\n") {
+				buf.WriteString("> " + line + "\n")
+			}
+			buf.WriteString("\n")
+		case "pre":
+			lang := ""
+			if class, ok := node.Find("code").Attr("class"); ok {
+				lang = strings.TrimPrefix(class, "language-")
+			}
+			buf.WriteString("```" + lang + "\n" + node.Text() + "\n```\n\n")
+		case "ul":
+			node.Find("li").Each(func(_ int, li *goquery.Selection) {
+				buf.WriteString("- " + strings.TrimSpace(li.Text()) + "\n")
+			})
+			buf.WriteString("\n")
+		case "ol":
+			node.Find("li").Each(func(i int, li *goquery.Selection) {
+				buf.WriteString(fmt.Sprintf("%d. %s\n", i+1, strings.TrimSpace(li.Text())))
+			})
+			buf.WriteString("\n")
+		case "table":
+			renderTable(node, buf)
+		case "img":
+			imgURL, _ := node.Attr("src")
+			shortcode, err := downloadImage(imgURL)
+			if err != nil {
+				log.Printf("Failed to download image: %v", err)
+				return
+			}
+			buf.WriteString(shortcode + "\n\n")
+		case "#text":
+			if text := node.Text(); strings.TrimSpace(text) != "" {
+				buf.WriteString(text)
+			}
+		default:
+			walkNodes(node.Contents(), buf)
+		}
+	})
+}
+
+func renderTable(table *goquery.Selection, buf *strings.Builder) {
+	rows := table.Find("tr")
+	rows.Each(func(i int, row *goquery.Selection) {
+		var cells []string
+		row.Find("th, td").Each(func(_ int, cell *goquery.Selection) {
+			cells = append(cells, strings.TrimSpace(cell.Text()))
+		})
+		buf.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+		if i == 0 {
+			sep := make([]string, len(cells))
+			for j := range sep {
+				sep[j] = "---"
+			}
+			buf.WriteString("| " + strings.Join(sep, " | ") + " |\n")
+		}
+	})
+	buf.WriteString("\n")
+}
+
+// createHugoContent generates Hugo Markdown content with front matter,
+// resolving category/tag IDs to names or slugs via the shared taxonomy cache.
 func createHugoContent(post Post) string {
 	frontMatter := fmt.Sprintf(`---
 title: "%s"
@@ -58,9 +257,22 @@ date: %s
 lastmod: %s
 slug: "%s"
 draft: false
----
 `, post.Title.Rendered, post.Date, post.Modified, post.Slug)
 
+	if len(post.Categories) > 0 {
+		frontMatter += "categories:\n"
+		for _, cat := range resolveTerms(post.Categories, taxonomy.Categories) {
+			frontMatter += fmt.Sprintf("  - %q\n", cat)
+		}
+	}
+	if len(post.Tags) > 0 {
+		frontMatter += "tags:\n"
+		for _, tag := range resolveTerms(post.Tags, taxonomy.Tags) {
+			frontMatter += fmt.Sprintf("  - %q\n", tag)
+		}
+	}
+	frontMatter += "---\n\n"
+
 	markdown := convertHTMLToMarkdown(post.Content.Rendered)
 	return frontMatter + markdown
 }
@@ -90,12 +302,24 @@ func handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	taxonomyMu.Lock()
+	if err := refreshTaxonomy(wpSiteURL, taxonomy); err != nil {
+		log.Printf("Taxonomy refresh failed, falling back to cached terms: %v", err)
+	} else if err := saveTaxonomyCache(taxonomyCachePath, taxonomy); err != nil {
+		log.Printf("Failed to persist taxonomy cache: %v", err)
+	}
+
 	filename := sanitizeFilename(post.Slug) + ".md"
 	outputDir := "content/posts"
 	filepath := filepath.Join(outputDir, filename)
 
 	content := createHugoContent(post)
 
+	postURL := fmt.Sprintf("%s/posts/%s/", wpSiteURL, post.Slug)
+	asObject := buildActivityStreamsObject(post.Title.Rendered, postURL, post.Content.Rendered,
+		post.Date, post.Modified, append(resolveTerms(post.Categories, taxonomy.Categories), resolveTerms(post.Tags, taxonomy.Tags)...))
+	taxonomyMu.Unlock()
+
 	err = os.MkdirAll(outputDir, os.ModePerm)
 	if err != nil {
 		http.Error(w, "Failed to create content directory", http.StatusInternalServerError)
@@ -108,11 +332,12 @@ func handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := writeActivityStreamsSibling(outputDir, sanitizeFilename(post.Slug), asObject); err != nil {
+		log.Printf("Failed to write ActivityStreams document for %s: %v", post.Slug, err)
+	}
+
 	// Trigger Hugo rebuild
-	cmd := exec.Command("hugo")
-	cmd.Dir = "./" // Change to your Hugo site directory
-	err = cmd.Run()
-	if err != nil {
+	if err := rebuildHugoCommand().Run(); err != nil {
 		http.Error(w, "Failed to rebuild Hugo site", http.StatusInternalServerError)
 		return
 	}
@@ -121,8 +346,20 @@ func handleWebhook(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// rebuildHugoCommand builds the `hugo` invocation used to regenerate the
+// site after content changes, shared by the webhook and Micropub handlers.
+func rebuildHugoCommand() *exec.Cmd {
+	cmd := exec.Command("hugo")
+	cmd.Dir = "./" // Change to your Hugo site directory
+	return cmd
+}
+
 func main() {
 	http.HandleFunc("/webhook", handleWebhook)
+	http.HandleFunc("/micropub", handleMicropub)
+	http.HandleFunc("/posts/", handlePostActivity)
+	http.HandleFunc("/actor", handleActor)
+	http.HandleFunc("/outbox", handleOutbox)
 	log.Println("Starting server on :8080...")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }